@@ -0,0 +1,128 @@
+// Copyright 2019 Google Cloud Platform Proxy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// DNSOpts configures how CreateDNSCluster resolves and re-resolves its
+// backend hostname.
+type DNSOpts struct {
+	// Logical selects LOGICAL_DNS discovery instead of the default
+	// STRICT_DNS. STRICT_DNS re-resolves on every refresh and
+	// reconciles the full set of addresses; LOGICAL_DNS keeps using the
+	// first resolved address until a connection to it fails.
+	Logical bool
+
+	// DNSRefreshRate is how often Envoy re-queries DNS. Leave zero to
+	// use Envoy's own default (5s).
+	DNSRefreshRate time.Duration
+
+	// DNSLookupFamily selects V4_ONLY, V6_ONLY, or AUTO. Leave zero for
+	// Envoy's default, AUTO (prefer IPv6, fall back to IPv4).
+	DNSLookupFamily clusterv3.Cluster_DnsLookupFamily
+
+	// RespectDNSTTL, when true, bounds DNSRefreshRate by the TTL
+	// returned in the DNS response.
+	RespectDNSTTL bool
+}
+
+// CreateDNSCluster creates a Cluster that resolves hostname via DNS
+// (STRICT_DNS by default, or LOGICAL_DNS if opts.Logical is set)
+// instead of relying on EDS or a static address, so Envoy tracks DNS
+// changes (and, with opts.RespectDNSTTL, DNS TTLs) for the backend
+// instead of assuming a single static IP.
+func CreateDNSCluster(name, hostname string, port uint32, opts DNSOpts) *clusterv3.Cluster {
+	discoveryType := clusterv3.Cluster_STRICT_DNS
+	if opts.Logical {
+		discoveryType = clusterv3.Cluster_LOGICAL_DNS
+	}
+
+	cluster := &clusterv3.Cluster{
+		Name: name,
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: discoveryType,
+		},
+		DnsLookupFamily: opts.DNSLookupFamily,
+		RespectDnsTtl:   opts.RespectDNSTTL,
+		LoadAssignment: CreateLoadAssignmentFromEndpoints(name, []Endpoint{{
+			Address: hostname,
+			Port:    port,
+		}}),
+	}
+	if opts.DNSRefreshRate != 0 {
+		cluster.DnsRefreshRate = durationpb.New(opts.DNSRefreshRate)
+	}
+	return cluster
+}
+
+// HealthCheckOpts configures the active health check WithHealthCheck
+// attaches to a Cluster.
+type HealthCheckOpts struct {
+	// HTTPPath, if set, configures an HttpHealthCheck against this
+	// path. Otherwise a TcpHealthCheck is configured.
+	HTTPPath string
+
+	// Interval, Timeout, UnhealthyThreshold and HealthyThreshold are
+	// left unset on the proto when zero, same as DNSOpts.DNSRefreshRate,
+	// rather than sent as explicit zeros: Envoy's proto validation
+	// rejects interval/timeout <= 0 and thresholds < 1, so callers must
+	// set all four.
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold uint32
+	HealthyThreshold   uint32
+}
+
+// WithHealthCheck attaches an active health check to cluster, using an
+// HttpHealthCheck against opts.HTTPPath when set, or a TcpHealthCheck
+// otherwise, and returns cluster so calls can be chained with
+// CreateDNSCluster.
+func WithHealthCheck(cluster *clusterv3.Cluster, opts HealthCheckOpts) *clusterv3.Cluster {
+	healthCheck := &corev3.HealthCheck{}
+	if opts.Interval != 0 {
+		healthCheck.Interval = durationpb.New(opts.Interval)
+	}
+	if opts.Timeout != 0 {
+		healthCheck.Timeout = durationpb.New(opts.Timeout)
+	}
+	if opts.UnhealthyThreshold != 0 {
+		healthCheck.UnhealthyThreshold = &wrapperspb.UInt32Value{Value: opts.UnhealthyThreshold}
+	}
+	if opts.HealthyThreshold != 0 {
+		healthCheck.HealthyThreshold = &wrapperspb.UInt32Value{Value: opts.HealthyThreshold}
+	}
+
+	if opts.HTTPPath != "" {
+		healthCheck.HealthChecker = &corev3.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{
+				Path: opts.HTTPPath,
+			},
+		}
+	} else {
+		healthCheck.HealthChecker = &corev3.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: &corev3.HealthCheck_TcpHealthCheck{},
+		}
+	}
+
+	cluster.HealthChecks = append(cluster.HealthChecks, healthCheck)
+	return cluster
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Google Cloud Platform Proxy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCreateDNSCluster(t *testing.T) {
+	tests := []struct {
+		desc     string
+		hostname string
+		port     uint32
+		opts     DNSOpts
+		want     *clusterv3.Cluster
+	}{
+		{
+			desc:     "STRICT_DNS by default, refresh rate unset at zero",
+			hostname: "oauth2.googleapis.com",
+			port:     443,
+			opts:     DNSOpts{},
+			want: &clusterv3.Cluster{
+				Name:                 "token-agent-cluster",
+				ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_STRICT_DNS},
+				LoadAssignment:       CreateLoadAssignmentFromEndpoints("token-agent-cluster", []Endpoint{{Address: "oauth2.googleapis.com", Port: 443}}),
+			},
+		},
+		{
+			desc:     "LOGICAL_DNS when Logical is set, refresh rate set when non-zero",
+			hostname: "oauth2.googleapis.com",
+			port:     443,
+			opts:     DNSOpts{Logical: true, DNSRefreshRate: 30 * time.Second, RespectDNSTTL: true},
+			want: &clusterv3.Cluster{
+				Name:                 "token-agent-cluster",
+				ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_LOGICAL_DNS},
+				DnsRefreshRate:       durationpb.New(30 * time.Second),
+				RespectDnsTtl:        true,
+				LoadAssignment:       CreateLoadAssignmentFromEndpoints("token-agent-cluster", []Endpoint{{Address: "oauth2.googleapis.com", Port: 443}}),
+			},
+		},
+		{
+			desc:     "load_assignment.cluster_name matches Cluster.Name, not hostname",
+			hostname: "servicecontrol.googleapis.com",
+			port:     443,
+			opts:     DNSOpts{},
+			want: &clusterv3.Cluster{
+				Name:                 "service-control-cluster",
+				ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_STRICT_DNS},
+				LoadAssignment:       CreateLoadAssignmentFromEndpoints("service-control-cluster", []Endpoint{{Address: "servicecontrol.googleapis.com", Port: 443}}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := CreateDNSCluster(test.want.Name, test.hostname, test.port, test.opts)
+			if !proto.Equal(got, test.want) {
+				t.Errorf("CreateDNSCluster() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithHealthCheck(t *testing.T) {
+	tests := []struct {
+		desc string
+		opts HealthCheckOpts
+		want *corev3.HealthCheck
+	}{
+		{
+			desc: "HTTPPath set configures an HttpHealthCheck",
+			opts: HealthCheckOpts{
+				HTTPPath:           "/healthz",
+				Interval:           5 * time.Second,
+				Timeout:            1 * time.Second,
+				UnhealthyThreshold: 3,
+				HealthyThreshold:   2,
+			},
+			want: &corev3.HealthCheck{
+				Interval:           durationpb.New(5 * time.Second),
+				Timeout:            durationpb.New(1 * time.Second),
+				UnhealthyThreshold: &wrapperspb.UInt32Value{Value: 3},
+				HealthyThreshold:   &wrapperspb.UInt32Value{Value: 2},
+				HealthChecker: &corev3.HealthCheck_HttpHealthCheck_{
+					HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{Path: "/healthz"},
+				},
+			},
+		},
+		{
+			desc: "HTTPPath unset configures a TcpHealthCheck",
+			opts: HealthCheckOpts{
+				Interval:           5 * time.Second,
+				Timeout:            1 * time.Second,
+				UnhealthyThreshold: 3,
+				HealthyThreshold:   2,
+			},
+			want: &corev3.HealthCheck{
+				Interval:           durationpb.New(5 * time.Second),
+				Timeout:            durationpb.New(1 * time.Second),
+				UnhealthyThreshold: &wrapperspb.UInt32Value{Value: 3},
+				HealthyThreshold:   &wrapperspb.UInt32Value{Value: 2},
+				HealthChecker: &corev3.HealthCheck_TcpHealthCheck_{
+					TcpHealthCheck: &corev3.HealthCheck_TcpHealthCheck{},
+				},
+			},
+		},
+		{
+			desc: "zero-valued interval/timeout/thresholds are left unset on the proto",
+			opts: HealthCheckOpts{},
+			want: &corev3.HealthCheck{
+				HealthChecker: &corev3.HealthCheck_TcpHealthCheck_{
+					TcpHealthCheck: &corev3.HealthCheck_TcpHealthCheck{},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cluster := &clusterv3.Cluster{Name: "cluster"}
+			got := WithHealthCheck(cluster, test.opts)
+			if got != cluster {
+				t.Errorf("WithHealthCheck() returned a different Cluster than was passed in")
+			}
+			if len(got.HealthChecks) != 1 {
+				t.Fatalf("WithHealthCheck() produced %d HealthChecks, want 1", len(got.HealthChecks))
+			}
+			if !proto.Equal(got.HealthChecks[0], test.want) {
+				t.Errorf("WithHealthCheck() HealthCheck = %v, want %v", got.HealthChecks[0], test.want)
+			}
+		})
+	}
+}
@@ -15,33 +15,125 @@
 package util
 
 import (
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	"github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
-// CreateLoadAssignment creates a ClusterLoadAssignment
-func CreateLoadAssignment(hostname string, port uint32) *v2.ClusterLoadAssignment {
-	return &v2.ClusterLoadAssignment{
-		ClusterName: hostname,
-		Endpoints: []endpoint.LocalityLbEndpoints{{
-			LbEndpoints: []endpoint.LbEndpoint{{
-				HostIdentifier: &endpoint.LbEndpoint_Endpoint{
-					Endpoint: &endpoint.Endpoint{
-						Address: &core.Address{
-							Address: &core.Address_SocketAddress{
-								SocketAddress: &core.SocketAddress{
-									Address: hostname,
-									PortSpecifier: &core.SocketAddress_PortValue{
-										PortValue: port,
+// Locality identifies the region/zone/sub-zone a backend Endpoint lives
+// in, mirroring Envoy's locality used for zone-aware routing.
+type Locality struct {
+	Region  string
+	Zone    string
+	SubZone string
+}
+
+// Endpoint describes a single backend address, together with the
+// locality, priority and weighting information Envoy needs for
+// weighted load balancing and priority failover.
+type Endpoint struct {
+	Address string
+	Port    uint32
+
+	// LoadBalancingWeight, when non-zero, is set on the LbEndpoint. The
+	// weights of all endpoints sharing a (Locality, Priority) are summed
+	// to produce the weight of the LocalityLbEndpoints they are grouped
+	// into.
+	LoadBalancingWeight uint32
+
+	// Locality and Priority determine how this Endpoint is grouped with
+	// others into LocalityLbEndpoints: endpoints sharing both fields
+	// land in the same group.
+	Locality Locality
+	Priority uint32
+
+	// HealthStatus overrides the endpoint's initial health status as
+	// seen by Envoy. Defaults to UNKNOWN (the zero value) if unset.
+	HealthStatus corev3.HealthStatus
+}
+
+// CreateLoadAssignment creates a ClusterLoadAssignment for a single
+// backend address. It is a thin wrapper around
+// CreateLoadAssignmentFromEndpoints for the common case of one endpoint
+// with no locality, priority or weighting information.
+func CreateLoadAssignment(hostname string, port uint32) *endpointv3.ClusterLoadAssignment {
+	return CreateLoadAssignmentFromEndpoints(hostname, []Endpoint{{
+		Address: hostname,
+		Port:    port,
+	}})
+}
+
+// CreateLoadAssignmentFromEndpoints creates a ClusterLoadAssignment from
+// a set of backend endpoints. Endpoints are grouped by (Locality,
+// Priority) into distinct LocalityLbEndpoints entries. For endpoints
+// with a non-zero weight, LoadBalancingWeight is set on both the
+// endpoint and its locality so Envoy can perform weighted, zone-aware
+// load balancing with priority failover across groups; a locality whose
+// endpoints are all unweighted has no LoadBalancingWeight.
+func CreateLoadAssignmentFromEndpoints(clusterName string, endpoints []Endpoint) *endpointv3.ClusterLoadAssignment {
+	type localityKey struct {
+		locality Locality
+		priority uint32
+	}
+
+	var order []localityKey
+	grouped := make(map[localityKey][]Endpoint)
+	for _, ep := range endpoints {
+		key := localityKey{locality: ep.Locality, priority: ep.Priority}
+		if _, ok := grouped[key]; !ok {
+			order = append(order, key)
+		}
+		grouped[key] = append(grouped[key], ep)
+	}
+
+	localityEndpoints := make([]*endpointv3.LocalityLbEndpoints, 0, len(order))
+	for _, key := range order {
+		group := grouped[key]
+
+		var localityWeight uint32
+		lbEndpoints := make([]*endpointv3.LbEndpoint, 0, len(group))
+		for _, ep := range group {
+			lbEndpoint := &endpointv3.LbEndpoint{
+				HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+					Endpoint: &endpointv3.Endpoint{
+						Address: &corev3.Address{
+							Address: &corev3.Address_SocketAddress{
+								SocketAddress: &corev3.SocketAddress{
+									Address: ep.Address,
+									PortSpecifier: &corev3.SocketAddress_PortValue{
+										PortValue: ep.Port,
 									},
 								},
 							},
 						},
 					},
 				},
+				HealthStatus: ep.HealthStatus,
+			}
+			if ep.LoadBalancingWeight != 0 {
+				lbEndpoint.LoadBalancingWeight = &wrapperspb.UInt32Value{Value: ep.LoadBalancingWeight}
+				localityWeight += ep.LoadBalancingWeight
+			}
+			lbEndpoints = append(lbEndpoints, lbEndpoint)
+		}
+
+		localityLbEndpoints := &endpointv3.LocalityLbEndpoints{
+			Locality: &corev3.Locality{
+				Region:  key.locality.Region,
+				Zone:    key.locality.Zone,
+				SubZone: key.locality.SubZone,
 			},
-			}},
-		},
+			LbEndpoints: lbEndpoints,
+			Priority:    key.priority,
+		}
+		if localityWeight != 0 {
+			localityLbEndpoints.LoadBalancingWeight = &wrapperspb.UInt32Value{Value: localityWeight}
+		}
+		localityEndpoints = append(localityEndpoints, localityLbEndpoints)
+	}
+
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: clusterName,
+		Endpoints:   localityEndpoints,
 	}
 }
@@ -0,0 +1,179 @@
+// Copyright 2019 Google Cloud Platform Proxy Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func lbEndpoint(address string, port uint32, weight uint32, healthStatus corev3.HealthStatus) *endpointv3.LbEndpoint {
+	ep := &endpointv3.LbEndpoint{
+		HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+			Endpoint: &endpointv3.Endpoint{
+				Address: &corev3.Address{
+					Address: &corev3.Address_SocketAddress{
+						SocketAddress: &corev3.SocketAddress{
+							Address: address,
+							PortSpecifier: &corev3.SocketAddress_PortValue{
+								PortValue: port,
+							},
+						},
+					},
+				},
+			},
+		},
+		HealthStatus: healthStatus,
+	}
+	if weight != 0 {
+		ep.LoadBalancingWeight = &wrapperspb.UInt32Value{Value: weight}
+	}
+	return ep
+}
+
+func TestCreateLoadAssignment(t *testing.T) {
+	got := CreateLoadAssignment("backend.example.com", 8080)
+	want := &endpointv3.ClusterLoadAssignment{
+		ClusterName: "backend.example.com",
+		Endpoints: []*endpointv3.LocalityLbEndpoints{{
+			Locality:    &corev3.Locality{},
+			LbEndpoints: []*endpointv3.LbEndpoint{lbEndpoint("backend.example.com", 8080, 0, 0)},
+		}},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("CreateLoadAssignment() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateLoadAssignmentFromEndpoints(t *testing.T) {
+	tests := []struct {
+		desc      string
+		endpoints []Endpoint
+		want      *endpointv3.ClusterLoadAssignment
+	}{
+		{
+			desc: "single unweighted endpoint",
+			endpoints: []Endpoint{
+				{Address: "10.0.0.1", Port: 80},
+			},
+			want: &endpointv3.ClusterLoadAssignment{
+				ClusterName: "cluster",
+				Endpoints: []*endpointv3.LocalityLbEndpoints{{
+					Locality:    &corev3.Locality{},
+					LbEndpoints: []*endpointv3.LbEndpoint{lbEndpoint("10.0.0.1", 80, 0, 0)},
+				}},
+			},
+		},
+		{
+			desc: "mixed weighted and unweighted endpoints in one locality sum only the weighted ones",
+			endpoints: []Endpoint{
+				{Address: "10.0.0.1", Port: 80, LoadBalancingWeight: 10},
+				{Address: "10.0.0.2", Port: 80},
+				{Address: "10.0.0.3", Port: 80, LoadBalancingWeight: 20},
+			},
+			want: &endpointv3.ClusterLoadAssignment{
+				ClusterName: "cluster",
+				Endpoints: []*endpointv3.LocalityLbEndpoints{{
+					Locality: &corev3.Locality{},
+					LbEndpoints: []*endpointv3.LbEndpoint{
+						lbEndpoint("10.0.0.1", 80, 10, 0),
+						lbEndpoint("10.0.0.2", 80, 0, 0),
+						lbEndpoint("10.0.0.3", 80, 20, 0),
+					},
+					LoadBalancingWeight: &wrapperspb.UInt32Value{Value: 30},
+				}},
+			},
+		},
+		{
+			desc: "all endpoints in a locality unweighted leaves the locality weight unset",
+			endpoints: []Endpoint{
+				{Address: "10.0.0.1", Port: 80},
+				{Address: "10.0.0.2", Port: 80},
+			},
+			want: &endpointv3.ClusterLoadAssignment{
+				ClusterName: "cluster",
+				Endpoints: []*endpointv3.LocalityLbEndpoints{{
+					Locality: &corev3.Locality{},
+					LbEndpoints: []*endpointv3.LbEndpoint{
+						lbEndpoint("10.0.0.1", 80, 0, 0),
+						lbEndpoint("10.0.0.2", 80, 0, 0),
+					},
+				}},
+			},
+		},
+		{
+			desc: "endpoints group by locality and priority, preserving first-seen order",
+			endpoints: []Endpoint{
+				{Address: "10.0.0.1", Port: 80, Locality: Locality{Region: "us-central1", Zone: "a"}, Priority: 0},
+				{Address: "10.0.1.1", Port: 80, Locality: Locality{Region: "us-central1", Zone: "b"}, Priority: 1},
+				{Address: "10.0.0.2", Port: 80, Locality: Locality{Region: "us-central1", Zone: "a"}, Priority: 0},
+				{Address: "10.0.2.1", Port: 80, Locality: Locality{Region: "us-central1", Zone: "a"}, Priority: 1},
+			},
+			want: &endpointv3.ClusterLoadAssignment{
+				ClusterName: "cluster",
+				Endpoints: []*endpointv3.LocalityLbEndpoints{
+					{
+						Locality: &corev3.Locality{Region: "us-central1", Zone: "a"},
+						LbEndpoints: []*endpointv3.LbEndpoint{
+							lbEndpoint("10.0.0.1", 80, 0, 0),
+							lbEndpoint("10.0.0.2", 80, 0, 0),
+						},
+						Priority: 0,
+					},
+					{
+						Locality:    &corev3.Locality{Region: "us-central1", Zone: "b"},
+						LbEndpoints: []*endpointv3.LbEndpoint{lbEndpoint("10.0.1.1", 80, 0, 0)},
+						Priority:    1,
+					},
+					{
+						Locality:    &corev3.Locality{Region: "us-central1", Zone: "a"},
+						LbEndpoints: []*endpointv3.LbEndpoint{lbEndpoint("10.0.2.1", 80, 0, 0)},
+						Priority:    1,
+					},
+				},
+			},
+		},
+		{
+			desc: "health status is preserved per endpoint",
+			endpoints: []Endpoint{
+				{Address: "10.0.0.1", Port: 80, HealthStatus: corev3.HealthStatus_HEALTHY},
+				{Address: "10.0.0.2", Port: 80, HealthStatus: corev3.HealthStatus_UNHEALTHY},
+			},
+			want: &endpointv3.ClusterLoadAssignment{
+				ClusterName: "cluster",
+				Endpoints: []*endpointv3.LocalityLbEndpoints{{
+					Locality: &corev3.Locality{},
+					LbEndpoints: []*endpointv3.LbEndpoint{
+						lbEndpoint("10.0.0.1", 80, 0, corev3.HealthStatus_HEALTHY),
+						lbEndpoint("10.0.0.2", 80, 0, corev3.HealthStatus_UNHEALTHY),
+					},
+				}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := CreateLoadAssignmentFromEndpoints("cluster", test.endpoints)
+			if !proto.Equal(got, test.want) {
+				t.Errorf("CreateLoadAssignmentFromEndpoints() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}